@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+)
+
+const (
+	// IssuerVault identifies the HashiCorp Vault issuer backend, for use as
+	// the `kind` passed to certificaterequests.New.
+	IssuerVault = "Vault"
+
+	// IssuerCMPv2 identifies the RFC 4210 CMPv2 issuer backend, for use as
+	// the `kind` passed to certificaterequests.New.
+	IssuerCMPv2 = "CMPv2"
+)
+
+// IssuerKind returns the Kind of the Issuer/ClusterIssuer a ref points at,
+// defaulting to "Issuer" when Kind is left unset, matching the API's
+// defaulting behaviour.
+func IssuerKind(ref v1alpha1.ObjectReference) string {
+	if ref.Kind == "" {
+		return "Issuer"
+	}
+
+	return ref.Kind
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CertificateRevocationRequest is a type to represent a request for
+// revocation of a previously issued certificate.
+type CertificateRevocationRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CertificateRevocationRequestSpec   `json:"spec,omitempty"`
+	Status CertificateRevocationRequestStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CertificateRevocationRequestList is a list of CertificateRevocationRequests.
+type CertificateRevocationRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CertificateRevocationRequest `json:"items"`
+}
+
+// CertificateRevocationRequestSpec defines the certificate to be revoked and
+// why.
+type CertificateRevocationRequestSpec struct {
+	// CertificateRequestRef refers to the CertificateRequest whose issued
+	// certificate should be revoked.
+	CertificateRequestRef LocalObjectReference `json:"certificateRequestRef"`
+
+	// Reason is the human/machine readable reason for this revocation
+	// request, e.g. "KeyCompromise", "Superseded".
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// CertificateRevocationRequestStatus reflects the observed state of a
+// CertificateRevocationRequest.
+type CertificateRevocationRequestStatus struct {
+	// Conditions describes the current state of this CertificateRevocationRequest.
+	// +optional
+	Conditions []CertificateRevocationRequestCondition `json:"conditions,omitempty"`
+
+	// RevocationTime is the time the certificate was confirmed revoked at
+	// the issuing CA.
+	// +optional
+	RevocationTime *metav1.Time `json:"revocationTime,omitempty"`
+
+	// Reason records the reason this revocation was performed for, copied
+	// from the request's spec at the time the revocation was confirmed.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// CertificateRevocationRequestCondition contains condition information for a
+// CertificateRevocationRequest.
+type CertificateRevocationRequestCondition struct {
+	// Type of the condition, currently ('Ready').
+	Type CertificateRevocationRequestConditionType `json:"type"`
+
+	// Status of the condition, one of ('True', 'False', 'Unknown').
+	Status ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the timestamp corresponding to the last status
+	// change of this condition.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a brief machine readable explanation for the condition's
+	// last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the details of the last
+	// transition, complementing reason.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CertificateRevocationRequestConditionType represents a
+// CertificateRevocationRequestCondition's type.
+type CertificateRevocationRequestConditionType string
+
+const (
+	// CertificateRevocationRequestConditionReady indicates that the
+	// revocation has been submitted to, and confirmed by, the issuer.
+	CertificateRevocationRequestConditionReady CertificateRevocationRequestConditionType = "Ready"
+)
+
+// LocalObjectReference references an object in the same namespace as the
+// referent, by name only.
+type LocalObjectReference struct {
+	// Name of the referent.
+	Name string `json:"name"`
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CMPv2Issuer describes the configuration required to issue certificates
+// from an RFC 4210 CMPv2 certificate authority, such as those used in
+// ONAP/telco deployments.
+type CMPv2Issuer struct {
+	// URL is the base URL of the CMPv2 server's enrollment endpoint.
+	URL string `json:"url"`
+
+	// CABundle is a PEM encoded TLS certificate bundle used to verify the
+	// remote CMPv2 server's certificate when sending CMP requests. This
+	// field is required if the CMPv2 server uses a self-signed certificate.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// SenderKID is the sender KeyIdentifier used to populate the
+	// PKIHeader.senderKID field of outgoing CMP requests.
+	// +optional
+	SenderKID string `json:"senderKID,omitempty"`
+
+	// RecipientKID is the recipient KeyIdentifier used to populate the
+	// PKIHeader.recipKID field of outgoing CMP requests.
+	// +optional
+	RecipientKID string `json:"recipientKID,omitempty"`
+
+	// Auth configures how this issuer authenticates requests to the
+	// CMPv2 server.
+	Auth CMPv2Auth `json:"auth"`
+}
+
+// CMPv2ClusterIssuer is identical to CMPv2Issuer, and is embedded in a
+// ClusterIssuer rather than an Issuer so that the config is accessible
+// cluster-wide.
+type CMPv2ClusterIssuer = CMPv2Issuer
+
+// CMPv2Auth configures the authentication protection used on the CMP
+// PKIMessages sent to the CA. Exactly one of SharedSecret or Signature
+// must be set.
+type CMPv2Auth struct {
+	// SharedSecret configures password-based MAC (PBM) protection, where
+	// the referenced Secret's `value` key holds the shared secret.
+	// +optional
+	SharedSecret *corev1.SecretKeySelector `json:"sharedSecretSecretRef,omitempty"`
+
+	// Signature configures signature-based protection, where the
+	// referenced Secret holds a `tls.crt`/`tls.key` pair used to sign
+	// outgoing PKIMessages.
+	// +optional
+	Signature *CMPv2SignatureAuth `json:"signature,omitempty"`
+}
+
+// CMPv2SignatureAuth references the signer certificate and private key
+// used to sign CMP PKIMessages when SignatureAlgorithm based protection
+// is configured.
+type CMPv2SignatureAuth struct {
+	// SecretName is the name of the Secret containing the signer's
+	// `tls.crt` and `tls.key` used to sign outgoing PKIMessages.
+	SecretName string `json:"secretName"`
+}
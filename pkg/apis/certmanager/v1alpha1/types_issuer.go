@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Issuer represents a namespace-scoped certificate signing authority.
+type Issuer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IssuerSpec   `json:"spec,omitempty"`
+	Status IssuerStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterIssuer represents a cluster-scoped certificate signing authority,
+// usable by Certificates/CertificateRequests in any namespace.
+type ClusterIssuer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IssuerSpec   `json:"spec,omitempty"`
+	Status IssuerStatus `json:"status,omitempty"`
+}
+
+// IssuerSpec describes the backend an Issuer/ClusterIssuer uses to sign
+// certificates. Exactly one backend should be configured.
+type IssuerSpec struct {
+	IssuerConfig `json:",inline"`
+}
+
+// IssuerConfig is the set of supported issuer backends.
+type IssuerConfig struct {
+	// Vault configures this issuer to sign certificates using a HashiCorp
+	// Vault PKI backend.
+	// +optional
+	Vault *VaultIssuer `json:"vault,omitempty"`
+
+	// CMPv2 configures this issuer to sign certificates using an RFC 4210
+	// CMPv2 certificate authority.
+	// +optional
+	CMPv2 *CMPv2Issuer `json:"cmpv2,omitempty"`
+}
+
+// IssuerStatus reflects the observed state of an Issuer/ClusterIssuer.
+type IssuerStatus struct {
+	// Conditions describes the current state of this issuer.
+	// +optional
+	Conditions []IssuerCondition `json:"conditions,omitempty"`
+}
+
+// IssuerCondition contains condition information for an Issuer/ClusterIssuer.
+type IssuerCondition struct {
+	// Type of the condition, currently ('Ready').
+	Type IssuerConditionType `json:"type"`
+
+	// Status of the condition, one of ('True', 'False', 'Unknown').
+	Status ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the timestamp corresponding to the last status
+	// change of this condition.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a brief machine readable explanation for the condition's
+	// last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the details of the last
+	// transition, complementing reason.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// IssuerConditionType represents an IssuerCondition's type.
+type IssuerConditionType string
+
+// ConditionStatus represents a condition's status.
+type ConditionStatus string
+
+const (
+	// ConditionTrue represents the fact that a given condition is true.
+	ConditionTrue ConditionStatus = "True"
+	// ConditionFalse represents the fact that a given condition is false.
+	ConditionFalse ConditionStatus = "False"
+	// ConditionUnknown represents the fact that a given condition is unknown.
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+const (
+	// IssuerConditionReady indicates that the issuer's backend has been
+	// validated and is ready to sign certificates.
+	IssuerConditionReady IssuerConditionType = "Ready"
+)
+
+// ObjectReference identifies the issuer a CertificateRequest is addressed
+// to, by name and, for cluster-scoped issuers, kind.
+type ObjectReference struct {
+	// Name of the Issuer/ClusterIssuer being referenced.
+	Name string `json:"name"`
+
+	// Kind of the resource being referenced, either "Issuer" or
+	// "ClusterIssuer". Defaults to "Issuer" when empty.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Group of the resource being referenced.
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// GenericIssuer is implemented by both Issuer and ClusterIssuer, letting
+// controllers operate on either without caring which they were given.
+type GenericIssuer interface {
+	GetObjectMeta() *metav1.ObjectMeta
+	GetSpec() *IssuerSpec
+	GetStatus() *IssuerStatus
+}
+
+func (i *Issuer) GetObjectMeta() *metav1.ObjectMeta { return &i.ObjectMeta }
+func (i *Issuer) GetSpec() *IssuerSpec              { return &i.Spec }
+func (i *Issuer) GetStatus() *IssuerStatus           { return &i.Status }
+
+func (c *ClusterIssuer) GetObjectMeta() *metav1.ObjectMeta { return &c.ObjectMeta }
+func (c *ClusterIssuer) GetSpec() *IssuerSpec              { return &c.Spec }
+func (c *ClusterIssuer) GetStatus() *IssuerStatus           { return &c.Status }
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// VaultIssuer contains configuration used to sign certificates using a
+// HashiCorp Vault PKI backend.
+type VaultIssuer struct {
+	// Server is the connection address for the Vault server, e.g.
+	// "https://vault.example.com:8200".
+	Server string `json:"server"`
+
+	// Path is the mount path of the Vault PKI backend's `sign` endpoint,
+	// e.g. "my_pki_mount/sign/my-role-name".
+	Path string `json:"path"`
+
+	// CABundle is a PEM encoded CA bundle used to validate Vault server
+	// certificates.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// Auth configures how cert-manager authenticates with the Vault server.
+	Auth VaultAuth `json:"auth"`
+
+	// SecretAccessGrants, when --enable-cross-namespace-issuer-secrets is
+	// set on the controller, allowlists the namespace/name pairs this
+	// issuer's auth Secret references are permitted to resolve to via the
+	// secondary API client. A cross-namespace secretRef that does not match
+	// any entry here is rejected even with the flag enabled. This is only
+	// consulted on a ClusterIssuer; Issuer-scoped Vault config may only
+	// reference Secrets in its own namespace.
+	// +optional
+	SecretAccessGrants []SecretAccessGrant `json:"secretAccessGrants,omitempty"`
+}
+
+// SecretAccessGrant permits a ClusterIssuer to resolve a cross-namespace
+// Secret reference via the controller's secondary, uncached API client.
+type SecretAccessGrant struct {
+	// Namespace of the Secret this grant permits access to.
+	Namespace string `json:"namespace"`
+
+	// Name of the Secret this grant permits access to.
+	Name string `json:"name"`
+}
+
+// VaultAuth is configuration used to authenticate with a Vault server.
+// Only one of TokenSecretRef, AppRole or Kubernetes may be specified.
+type VaultAuth struct {
+	// TokenSecretRef authenticates with Vault by presenting a client token.
+	// +optional
+	TokenSecretRef *VaultTokenSecretRef `json:"tokenSecretRef,omitempty"`
+
+	// AppRole authenticates with Vault using the AppRole auth mechanism,
+	// with the role and secret stored in a Kubernetes Secret resource.
+	// +optional
+	AppRole *VaultAppRole `json:"appRole,omitempty"`
+
+	// Kubernetes authenticates with Vault by passing the ServiceAccount
+	// token stored in the named Secret resource to the Vault server's
+	// Kubernetes auth method.
+	// +optional
+	Kubernetes *VaultKubernetesAuth `json:"kubernetes,omitempty"`
+}
+
+// VaultTokenSecretRef references the Secret containing the Vault client
+// token used to authenticate.
+type VaultTokenSecretRef struct {
+	SecretKeySelector `json:",inline"`
+
+	// Namespace of the referenced Secret. If set, and differs from the
+	// namespace of the Issuer/CertificateRequest, the Secret is looked up
+	// via the controller's secondary API client rather than the shared
+	// informer cache, and must be permitted by a SecretAccessGrant on the
+	// ClusterIssuer. Requires --enable-cross-namespace-issuer-secrets.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// VaultAppRole authenticates with Vault using the AppRole auth mechanism.
+type VaultAppRole struct {
+	// Path is the mount path of the AppRole auth method.
+	Path string `json:"path"`
+
+	// RoleID of the AppRole.
+	RoleID string `json:"roleId"`
+
+	// SecretRef references the Secret containing the AppRole SecretID.
+	SecretRef VaultSecretKeySelector `json:"secretRef"`
+}
+
+// VaultKubernetesAuth authenticates with Vault using the Kubernetes auth
+// method, presenting the ServiceAccount token stored in SecretRef.
+type VaultKubernetesAuth struct {
+	// Path is the mount path of the Kubernetes auth method.
+	Path string `json:"path"`
+
+	// Role is the Vault role to authenticate against.
+	Role string `json:"role"`
+
+	// SecretRef references the Secret containing the service account JWT
+	// used to authenticate against the Vault server.
+	SecretRef VaultSecretKeySelector `json:"secretRef"`
+}
+
+// VaultSecretKeySelector is a reference to a Secret key, with an optional
+// explicit Namespace used by the cross-namespace-issuer-secrets feature to
+// fetch the Secret via the controller's secondary Kubernetes client instead
+// of the shared informer cache.
+type VaultSecretKeySelector struct {
+	SecretKeySelector `json:",inline"`
+
+	// Namespace of the referenced Secret. If set, and differs from the
+	// namespace of the Issuer/CertificateRequest, the Secret is looked up
+	// via the controller's secondary API client rather than the shared
+	// informer cache, and must be permitted by a SecretAccessGrant on the
+	// ClusterIssuer. Requires --enable-cross-namespace-issuer-secrets.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// SecretKeySelector selects a key of a Secret.
+type SecretKeySelector struct {
+	// Name of the Secret resource being referenced.
+	Name string `json:"name"`
+
+	// Key of the entry in the Secret resource's `data` field to be used.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
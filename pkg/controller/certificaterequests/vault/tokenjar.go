@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+	vaultinternal "github.com/jetstack/cert-manager/pkg/internal/vault"
+)
+
+// renewBeforeExpiry is how far ahead of a token's lease expiring the jar
+// proactively renews it rather than waiting to hit a dead token.
+const renewBeforeExpiry = 30 * time.Second
+
+var (
+	tokenJarCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certmanager_vault_token_cache_hits_total",
+		Help: "Number of Vault Sign calls that reused a cached, still-valid login token.",
+	})
+	tokenJarRenewals = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certmanager_vault_token_cache_renewals_total",
+		Help: "Number of times a cached Vault login token was renewed via auth/token/renew-self.",
+	})
+	tokenJarForcedRelogins = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certmanager_vault_token_cache_forced_relogins_total",
+		Help: "Number of times the Vault token cache was missed or invalidated, forcing a fresh login.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(tokenJarCacheHits, tokenJarRenewals, tokenJarForcedRelogins)
+}
+
+// tokenJarKey identifies a cached Vault login: a given issuer, using a given
+// auth method, backed by a given version of its auth Secret. Bumping the
+// Secret's ResourceVersion (a credential rotation) naturally mints a new
+// key, so stale entries are simply never looked up again rather than
+// requiring an explicit eviction pass.
+type tokenJarKey struct {
+	issuerUID             types.UID
+	authMethod            string
+	secretResourceVersion string
+}
+
+// tokenJarEntry is a cached Vault login token along with enough metadata to
+// decide whether it can still be reused. policies is recorded for
+// observability only: real Vault policies are named independently of the
+// PKI path being signed against (e.g. "pki-signer"), so there's no reliable
+// way for the client to predict from a policy's name alone whether it
+// authorizes a given `pki/sign/<role>` path. Whether the token is actually
+// authorized is instead left to Vault itself to enforce on each Sign call.
+type tokenJarEntry struct {
+	token     string
+	expiresAt time.Time
+	renewable bool
+	policies  []string
+}
+
+func (e *tokenJarEntry) expired() bool {
+	return time.Now().After(e.expiresAt)
+}
+
+func (e *tokenJarEntry) nearExpiry() bool {
+	return time.Now().After(e.expiresAt.Add(-renewBeforeExpiry))
+}
+
+// tokenJar caches Vault login tokens across Sign calls so that repeated
+// CertificateRequests against the same issuer don't each pay the cost (and
+// rate-limit risk) of a fresh Vault login.
+type tokenJar struct {
+	mu      sync.Mutex
+	entries map[tokenJarKey]*tokenJarEntry
+}
+
+func newTokenJar() *tokenJar {
+	return &tokenJar{
+		entries: make(map[tokenJarKey]*tokenJarEntry),
+	}
+}
+
+// sharedTokenJar is the single tokenJar instance used by every Vault
+// controller in this process, so that a credential rotation observed by the
+// issuers/vaultauth controller can invalidate the exact cache entries the
+// certificaterequests/vault controller's Sign calls consult.
+var sharedTokenJar = newTokenJar()
+
+// InvalidateIssuerTokens drops every cached Vault login token for issuerUID,
+// regardless of auth method or auth Secret version. Called by the
+// issuers/vaultauth controller when it detects that an issuer's Vault auth
+// Secret has changed, so that signings and revocations immediately after a
+// credential rotation don't race against a still-cached, now-stale token.
+func InvalidateIssuerTokens(issuerUID types.UID) {
+	sharedTokenJar.mu.Lock()
+	defer sharedTokenJar.mu.Unlock()
+
+	for key := range sharedTokenJar.entries {
+		if key.issuerUID == issuerUID {
+			delete(sharedTokenJar.entries, key)
+		}
+	}
+}
+
+// clientFor returns a Vault client for issuer's Vault config, reusing a
+// cached token when one exists and is unexpired; renewing it if it's
+// renewable and close to expiry; or logging in from scratch otherwise.
+func (j *tokenJar) clientFor(issuer v1alpha1.GenericIssuer, namespace string, secretsLister corelisters.SecretLister, secondaryClient kubernetes.Interface, vaultSpec *v1alpha1.VaultIssuer) (*vaultinternal.Client, error) {
+	issuerUID := issuer.GetObjectMeta().UID
+
+	_, authMethod, _, err := vaultinternal.AuthSecretRef(vaultSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolved the same way login's cross-namespace secretRefs are: a plain
+	// secretsLister lookup can't see a Secret outside the namespaces the
+	// shared informers watch, so cache-keying has to go through the same
+	// secondaryClient/secretAccessGrants-aware path Sign itself ends up using.
+	secret, err := vaultinternal.AuthSecret(namespace, secretsLister, secondaryClient, vaultSpec, vaultinternal.IsClusterScoped(issuer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault auth secret: %s", err.Error())
+	}
+
+	key := tokenJarKey{
+		issuerUID:             issuerUID,
+		authMethod:            authMethod,
+		secretResourceVersion: secret.ResourceVersion,
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok := j.entries[key]
+
+	switch {
+	case ok && !entry.expired():
+		if entry.nearExpiry() && entry.renewable {
+			if renewed, err := j.renew(vaultSpec, entry); err == nil {
+				j.entries[key] = renewed
+				tokenJarRenewals.Inc()
+				return vaultinternal.NewWithToken(vaultSpec, renewed.token)
+			}
+			// fall through to a fresh login if renewal failed.
+		} else {
+			tokenJarCacheHits.Inc()
+			return vaultinternal.NewWithToken(vaultSpec, entry.token)
+		}
+	}
+
+	tokenJarForcedRelogins.Inc()
+
+	info, err := vaultinternal.Login(namespace, secretsLister, secondaryClient, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	newEntry := &tokenJarEntry{
+		token:     info.Token,
+		expiresAt: time.Now().Add(info.LeaseDuration),
+		renewable: info.Renewable,
+		policies:  info.Policies,
+	}
+	j.entries[key] = newEntry
+
+	return vaultinternal.NewWithToken(vaultSpec, newEntry.token)
+}
+
+func (j *tokenJar) renew(vaultSpec *v1alpha1.VaultIssuer, entry *tokenJarEntry) (*tokenJarEntry, error) {
+	info, err := vaultinternal.RenewSelf(vaultSpec, entry.token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenJarEntry{
+		token:     info.Token,
+		expiresAt: time.Now().Add(info.LeaseDuration),
+		renewable: info.Renewable,
+		policies:  info.Policies,
+	}, nil
+}
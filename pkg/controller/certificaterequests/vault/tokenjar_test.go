@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenJarEntryExpired(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{"already expired", time.Now().Add(-time.Minute), true},
+		{"still valid", time.Now().Add(time.Hour), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := &tokenJarEntry{expiresAt: test.expiresAt}
+			if got := entry.expired(); got != test.want {
+				t.Errorf("expired() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTokenJarEntryNearExpiry(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{"far from expiry", time.Now().Add(time.Hour), false},
+		{"within renewBeforeExpiry window", time.Now().Add(renewBeforeExpiry / 2), true},
+		{"already expired", time.Now().Add(-time.Minute), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := &tokenJarEntry{expiresAt: test.expiresAt}
+			if got := entry.nearExpiry(); got != test.want {
+				t.Errorf("nearExpiry() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInvalidateIssuerTokensOnlyDropsMatchingUID(t *testing.T) {
+	jar := newTokenJar()
+
+	const targetUID = "issuer-a"
+	jar.entries[tokenJarKey{issuerUID: targetUID, authMethod: "token", secretResourceVersion: "1"}] = &tokenJarEntry{token: "stale"}
+	jar.entries[tokenJarKey{issuerUID: "issuer-b", authMethod: "token", secretResourceVersion: "1"}] = &tokenJarEntry{token: "unrelated"}
+
+	sharedTokenJar = jar
+	InvalidateIssuerTokens(targetUID)
+
+	for key := range jar.entries {
+		if key.issuerUID == targetUID {
+			t.Errorf("entry for issuer %q survived invalidation", targetUID)
+		}
+	}
+	if len(jar.entries) != 1 {
+		t.Errorf("expected only the unrelated issuer's entry to remain, got %d entries", len(jar.entries))
+	}
+}
@@ -19,8 +19,11 @@ package vault
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"strings"
 
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/record"
 
@@ -44,6 +47,20 @@ type Vault struct {
 	recorder      record.EventRecorder
 	secretsLister corelisters.SecretLister
 	helper        issuer.Helper
+
+	// secondaryClient, when set, is used to fetch Vault auth Secrets
+	// directly from the API server for issuers that reference a Secret
+	// outside the namespaces the shared informers watch. It is only
+	// populated when --enable-cross-namespace-issuer-secrets is set.
+	secondaryClient kubernetes.Interface
+
+	// tokenJar caches Vault login tokens across Sign calls, keyed by
+	// issuer, auth method and auth Secret ResourceVersion, so repeated
+	// signings against the same issuer don't each re-authenticate to Vault.
+	// It is always set to sharedTokenJar, so that the issuers/vaultauth
+	// controller can invalidate entries on credential rotation regardless
+	// of which Vault controller instance originally cached them.
+	tokenJar *tokenJar
 }
 
 func init() {
@@ -63,14 +80,24 @@ func init() {
 }
 
 func NewVault(ctx *controllerpkg.Context) *Vault {
-	return &Vault{
+	v := &Vault{
 		recorder:      ctx.Recorder,
 		secretsLister: ctx.KubeSharedInformerFactory.Core().V1().Secrets().Lister(),
 		helper: issuer.NewHelper(
 			ctx.SharedInformerFactory.Certmanager().V1alpha1().Issuers().Lister(),
 			ctx.SharedInformerFactory.Certmanager().V1alpha1().ClusterIssuers().Lister(),
 		),
+		tokenJar: sharedTokenJar,
+	}
+
+	// Only hand the Vault client a direct, uncached API client when the
+	// operator has explicitly opted in; otherwise cross-namespace secretRefs
+	// are rejected rather than silently falling back to the informer cache.
+	if ctx.EnableCrossNamespaceIssuerSecrets {
+		v.secondaryClient = ctx.Client
 	}
+
+	return v
 }
 
 func (v *Vault) Sign(ctx context.Context, cr *v1alpha1.CertificateRequest) (*issuer.IssueResponse, error) {
@@ -103,7 +130,14 @@ func (v *Vault) Sign(ctx context.Context, cr *v1alpha1.CertificateRequest) (*iss
 		return nil, nil
 	}
 
-	client, err := vaultinternal.New(cr.Namespace, v.secretsLister, issuerObj)
+	vaultSpec := issuerObj.GetSpec().Vault
+	if vaultSpec == nil {
+		reporter.Failed(fmt.Errorf("vault config may not be empty"), "ErrorVaultInit",
+			"Failed to initialise vault client for signing: vault config may not be empty")
+		return nil, nil
+	}
+
+	client, err := v.tokenJar.clientFor(issuerObj, cr.Namespace, v.secretsLister, v.secondaryClient, vaultSpec)
 	if err != nil {
 		reporter.Pending(err, "ErrorVaultInit",
 			fmt.Sprintf("Failed to initialise vault client for signing: %s", err))
@@ -124,3 +158,48 @@ func (v *Vault) Sign(ctx context.Context, cr *v1alpha1.CertificateRequest) (*iss
 		CA:          caPem,
 	}, nil
 }
+
+// Revoke revokes the certificate previously issued for cr via Vault's
+// pki/revoke endpoint, implementing issuer.Revoker.
+func (v *Vault) Revoke(ctx context.Context, cr *v1alpha1.CertificateRequest) error {
+	log := logf.FromContext(ctx, "revoke")
+
+	issuerObj, err := v.helper.GetGenericIssuer(cr.Spec.IssuerRef, cr.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get issuer %s referenced by certificaterequest %s/%s: %s",
+			apiutil.IssuerKind(cr.Spec.IssuerRef), cr.Namespace, cr.Name, err)
+	}
+
+	cert, err := pki.DecodeX509CertificateBytes(cr.Status.Certificate)
+	if err != nil {
+		return fmt.Errorf("failed to decode issued certificate for certificaterequest %s/%s: %s", cr.Namespace, cr.Name, err)
+	}
+
+	client, err := vaultinternal.New(cr.Namespace, v.secretsLister, v.secondaryClient, issuerObj)
+	if err != nil {
+		return fmt.Errorf("failed to initialise vault client for revocation: %s", err)
+	}
+
+	serialNumber := formatSerialNumber(cert.SerialNumber)
+
+	if err := client.Revoke(serialNumber); err != nil {
+		return fmt.Errorf("vault failed to revoke certificate with serial %q: %s", serialNumber, err)
+	}
+
+	log.Info("certificate revoked", "serialNumber", serialNumber)
+
+	return nil
+}
+
+// formatSerialNumber renders a certificate serial number the way Vault's
+// pki/revoke endpoint expects it: lower-case hex octets separated by colons.
+func formatSerialNumber(serial *big.Int) string {
+	raw := serial.Bytes()
+
+	hexBytes := make([]string, len(raw))
+	for i, b := range raw {
+		hexBytes[i] = fmt.Sprintf("%02x", b)
+	}
+
+	return strings.Join(hexBytes, ":")
+}
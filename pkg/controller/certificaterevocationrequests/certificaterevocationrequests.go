@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certificaterevocationrequests provides the generic
+// CertificateRevocationRequest informer/workqueue wiring shared by every
+// issuer-specific revocation backend, mirroring the role
+// certificaterequests.New(kind, signer) plays for the CertificateRequest
+// Sign controllers.
+package certificaterevocationrequests
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+	cmlisters "github.com/jetstack/cert-manager/pkg/client/listers/certmanager/v1alpha1"
+	controllerpkg "github.com/jetstack/cert-manager/pkg/controller"
+)
+
+const queueName = "certificaterevocationrequests"
+
+// Revoker reconciles a single CertificateRevocationRequest against whatever
+// backend issued the certificate it asks to revoke.
+type Revoker interface {
+	Reconcile(ctx context.Context, crr *v1alpha1.CertificateRevocationRequest) error
+}
+
+// controller adapts a Revoker to controllerpkg.Interface by watching
+// CertificateRevocationRequests and dispatching each one to the Revoker.
+type controller struct {
+	revoker Revoker
+	lister  cmlisters.CertificateRevocationRequestLister
+	queue   workqueue.RateLimitingInterface
+}
+
+// New returns a controllerpkg.Interface that reconciles every
+// CertificateRevocationRequest by calling revoker.Reconcile, the same way
+// certificaterequests.New(kind, signer) adapts a Signer to the
+// CertificateRequest informer.
+func New(revoker Revoker) controllerpkg.Interface {
+	return &controller{revoker: revoker}
+}
+
+func (c *controller) Register(ctx *controllerpkg.Context) (workqueue.RateLimitingInterface, []cache.InformerSynced, error) {
+	c.queue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), queueName)
+
+	informer := ctx.SharedInformerFactory.Certmanager().V1alpha1().CertificateRevocationRequests()
+	informer.Informer().AddEventHandler(&controllerpkg.QueuingEventHandler{Queue: c.queue})
+	c.lister = informer.Lister()
+
+	return c.queue, []cache.InformerSynced{informer.Informer().HasSynced}, nil
+}
+
+func (c *controller) ProcessItem(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	crr, err := c.lister.CertificateRevocationRequests(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.revoker.Reconcile(ctx, crr)
+}
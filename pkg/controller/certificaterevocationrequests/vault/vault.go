@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vault reconciles CertificateRevocationRequests whose originating
+// CertificateRequest was issued via the Vault issuer, submitting the
+// revocation to Vault's pki/revoke endpoint.
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+	cmclientset "github.com/jetstack/cert-manager/pkg/client/clientset/versioned"
+	controllerpkg "github.com/jetstack/cert-manager/pkg/controller"
+	vaultissuer "github.com/jetstack/cert-manager/pkg/controller/certificaterequests/vault"
+	"github.com/jetstack/cert-manager/pkg/controller/certificaterevocationrequests"
+	logf "github.com/jetstack/cert-manager/pkg/logs"
+)
+
+const (
+	CRRControllerName = "certificaterevocationrequests-issuer-vault"
+)
+
+// Vault reconciles CertificateRevocationRequests by delegating the actual
+// pki/revoke call to the Vault issuer type already used for signing, which
+// knows how to build an authenticated Vault client for an issuer.
+type Vault struct {
+	recorder    record.EventRecorder
+	cmClient    cmclientset.Interface
+	vaultIssuer *vaultissuer.Vault
+}
+
+func init() {
+	controllerpkg.Register(CRRControllerName, func(ctx *controllerpkg.Context) (controllerpkg.Interface, error) {
+		v := NewVault(ctx)
+
+		controller := certificaterevocationrequests.New(v)
+
+		c, err := controllerpkg.New(ctx, CRRControllerName, controller)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.Run, nil
+	})
+}
+
+func NewVault(ctx *controllerpkg.Context) *Vault {
+	return &Vault{
+		recorder:    ctx.Recorder,
+		cmClient:    ctx.CMClient,
+		vaultIssuer: vaultissuer.NewVault(ctx),
+	}
+}
+
+// Reconcile resolves the CertificateRequest referenced by crr, asks the
+// Vault issuer to revoke the certificate it issued, and records the
+// revocation timestamp and reason on crr's status. Exported to satisfy
+// certificaterevocationrequests.Revoker.
+func (v *Vault) Reconcile(ctx context.Context, crr *v1alpha1.CertificateRevocationRequest) error {
+	log := logf.FromContext(ctx, "revoke")
+
+	cr, err := v.cmClient.CertmanagerV1alpha1().CertificateRequests(crr.Namespace).Get(ctx, crr.Spec.CertificateRequestRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get certificaterequest %s/%s referenced by certificaterevocationrequest %s: %s",
+			crr.Namespace, crr.Spec.CertificateRequestRef.Name, crr.Name, err)
+	}
+
+	if err := v.vaultIssuer.Revoke(ctx, cr); err != nil {
+		return fmt.Errorf("vault failed to revoke certificate: %s", err)
+	}
+
+	now := metav1.NewTime(time.Now())
+	crr.Status.RevocationTime = &now
+	crr.Status.Reason = crr.Spec.Reason
+
+	if _, err := v.cmClient.CertmanagerV1alpha1().CertificateRevocationRequests(crr.Namespace).UpdateStatus(ctx, crr, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update certificaterevocationrequest %s/%s status: %s", crr.Namespace, crr.Name, err)
+	}
+
+	log.Info("certificate revoked", "certificateRequest", cr.Name, "reason", crr.Spec.Reason)
+
+	return nil
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vaultauth watches Issuers and ClusterIssuers configured with a
+// Vault backend and invalidates any cached Vault client/token for one when
+// its referenced auth Secret's contents change, so that revocations and
+// signings performed shortly after a credential rotation don't fail
+// silently against a now-stale cached token.
+//
+// Changes are detected by hashing the referenced Secret's data and
+// comparing it against a hash stored in an annotation on the Issuer's
+// status, the same migration-hash technique used by Gardener's
+// cert-management controller to detect drift without needing to diff
+// full objects. This controller only watches Issuer/ClusterIssuer events, so
+// a rotation is caught the next time the issuer is reconciled, including via
+// the informer's periodic resync — not immediately off a Secret event.
+package vaultauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+	cmclientset "github.com/jetstack/cert-manager/pkg/client/clientset/versioned"
+	cmlisters "github.com/jetstack/cert-manager/pkg/client/listers/certmanager/v1alpha1"
+	controllerpkg "github.com/jetstack/cert-manager/pkg/controller"
+	vaultissuer "github.com/jetstack/cert-manager/pkg/controller/certificaterequests/vault"
+	vaultinternal "github.com/jetstack/cert-manager/pkg/internal/vault"
+	logf "github.com/jetstack/cert-manager/pkg/logs"
+)
+
+const (
+	ControllerName = "issuers-vault-auth-secret-watcher"
+
+	// secretHashAnnotation stores the last-observed hash of the Issuer's
+	// Vault auth Secret data, so a subsequent reconcile can tell whether
+	// the Secret changed since cached credentials were last (in)validated.
+	secretHashAnnotation = "certmanager.k8s.io/vault-auth-secret-hash"
+)
+
+// controller reconciles Issuers/ClusterIssuers configured with a Vault
+// backend, re-hashing their referenced auth Secret on every Secret or
+// Issuer change and invalidating cached Vault credentials on drift.
+type controller struct {
+	secretsLister       corelisters.SecretLister
+	issuerLister        cmlisters.IssuerLister
+	clusterIssuerLister cmlisters.ClusterIssuerLister
+	cmClient            cmclientset.Interface
+	queue               workqueue.RateLimitingInterface
+
+	// secondaryClient, when set, resolves a Vault auth Secret directly from
+	// the API server for issuers whose auth config references a Secret
+	// outside the namespaces the shared informers watch. It is only
+	// populated when --enable-cross-namespace-issuer-secrets is set, mirroring
+	// certificaterequests/vault.Vault's own secondaryClient wiring.
+	secondaryClient kubernetes.Interface
+}
+
+func init() {
+	controllerpkg.Register(ControllerName, func(ctx *controllerpkg.Context) (controllerpkg.Interface, error) {
+		c := &controller{
+			secretsLister: ctx.KubeSharedInformerFactory.Core().V1().Secrets().Lister(),
+			cmClient:      ctx.CMClient,
+		}
+
+		if ctx.EnableCrossNamespaceIssuerSecrets {
+			c.secondaryClient = ctx.Client
+		}
+
+		ctrl, err := controllerpkg.New(ctx, ControllerName, c)
+		if err != nil {
+			return nil, err
+		}
+
+		return ctrl.Run, nil
+	})
+}
+
+// Register wires up the Issuer and ClusterIssuer informers, queuing both
+// kinds of object onto a single workqueue keyed the way
+// cache.DeletionHandlingMetaNamespaceKeyFunc keys them: "namespace/name" for
+// a namespaced Issuer, or bare "name" for a cluster-scoped ClusterIssuer.
+// ProcessItem tells the two apart by whether the key carries a namespace.
+func (c *controller) Register(ctx *controllerpkg.Context) (workqueue.RateLimitingInterface, []cache.InformerSynced, error) {
+	c.queue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ControllerName)
+
+	issuerInformer := ctx.SharedInformerFactory.Certmanager().V1alpha1().Issuers()
+	issuerInformer.Informer().AddEventHandler(&controllerpkg.QueuingEventHandler{Queue: c.queue})
+	c.issuerLister = issuerInformer.Lister()
+
+	clusterIssuerInformer := ctx.SharedInformerFactory.Certmanager().V1alpha1().ClusterIssuers()
+	clusterIssuerInformer.Informer().AddEventHandler(&controllerpkg.QueuingEventHandler{Queue: c.queue})
+	c.clusterIssuerLister = clusterIssuerInformer.Lister()
+
+	return c.queue, []cache.InformerSynced{
+		issuerInformer.Informer().HasSynced,
+		clusterIssuerInformer.Informer().HasSynced,
+	}, nil
+}
+
+// ProcessItem looks up the Issuer or ClusterIssuer identified by key and
+// reconciles it.
+func (c *controller) ProcessItem(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	var issuerObj v1alpha1.GenericIssuer
+	if namespace == "" {
+		clusterIssuer, err := c.clusterIssuerLister.Get(name)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		issuerObj = clusterIssuer
+	} else {
+		issuer, err := c.issuerLister.Issuers(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		issuerObj = issuer
+	}
+
+	return c.reconcile(ctx, issuerObj)
+}
+
+// reconcile re-hashes issuerObj's Vault auth Secret and, if it differs from
+// the hash recorded on the issuer's status, invalidates any cached Vault
+// token for this issuer and records the new hash.
+func (c *controller) reconcile(ctx context.Context, issuerObj v1alpha1.GenericIssuer) error {
+	log := logf.FromContext(ctx, "vaultauth")
+
+	vaultSpec := issuerObj.GetSpec().Vault
+	if vaultSpec == nil {
+		return nil
+	}
+
+	hash, err := c.hashAuthSecret(issuerObj, vaultSpec)
+	if err != nil {
+		return fmt.Errorf("failed to hash vault auth secret for issuer %q: %s", issuerObj.GetObjectMeta().Name, err)
+	}
+
+	annotations := issuerObj.GetObjectMeta().Annotations
+	previous := annotations[secretHashAnnotation]
+
+	if previous == hash {
+		return nil
+	}
+
+	vaultissuer.InvalidateIssuerTokens(issuerObj.GetObjectMeta().UID)
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[secretHashAnnotation] = hash
+	issuerObj.GetObjectMeta().Annotations = annotations
+
+	log.Info("vault auth secret changed, invalidated cached token", "issuer", issuerObj.GetObjectMeta().Name)
+
+	return c.updateIssuer(ctx, issuerObj)
+}
+
+// updateIssuer persists issuerObj's updated annotations back to the API
+// server, dispatching to the Issuer or ClusterIssuer client depending on
+// its concrete type.
+func (c *controller) updateIssuer(ctx context.Context, issuerObj v1alpha1.GenericIssuer) error {
+	switch t := issuerObj.(type) {
+	case *v1alpha1.Issuer:
+		_, err := c.cmClient.CertmanagerV1alpha1().Issuers(t.Namespace).Update(ctx, t, metav1.UpdateOptions{})
+		return err
+	case *v1alpha1.ClusterIssuer:
+		_, err := c.cmClient.CertmanagerV1alpha1().ClusterIssuers().Update(ctx, t, metav1.UpdateOptions{})
+		return err
+	default:
+		return fmt.Errorf("unknown issuer type %T", issuerObj)
+	}
+}
+
+// hashAuthSecret fetches whichever Secret is referenced by vaultSpec's auth
+// config and returns a stable hash of its data. It resolves the Secret via
+// vaultinternal.AuthSecret, the same cross-namespace/secretAccessGrants-aware
+// path login itself uses, so a ClusterIssuer's cross-namespace auth Secret is
+// hashed correctly instead of 404ing or matching an unrelated same-named
+// Secret in the issuer's own namespace.
+func (c *controller) hashAuthSecret(issuerObj v1alpha1.GenericIssuer, vaultSpec *v1alpha1.VaultIssuer) (string, error) {
+	secret, err := vaultinternal.AuthSecret(issuerObj.GetObjectMeta().Namespace, c.secretsLister, c.secondaryClient, vaultSpec, vaultinternal.IsClusterScoped(issuerObj))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(secret.Data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
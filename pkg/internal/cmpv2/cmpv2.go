@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cmpv2 implements a minimal RFC 4210 CMPv2 client sufficient to
+// enroll a certificate from a CSR: it builds an `ir` (or `cr`, depending on
+// the configured body type) PKIMessage, POSTs it to the configured CA
+// endpoint, parses the returned `ip`/`cp` PKIMessage, and confirms receipt
+// with a `certConf`/`PKIConfirm` exchange as required by the protocol.
+package cmpv2
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+	"github.com/jetstack/cert-manager/pkg/internal/cmpv2/pkimessage"
+)
+
+const cmpContentType = "application/pkixcmp"
+
+// TransportError indicates the request/response round trip to the CMPv2
+// server itself failed (connection refused, TLS handshake, non-200 status),
+// as distinct from the CA rejecting or mangling the CMP exchange.
+type TransportError struct {
+	err error
+}
+
+func (e *TransportError) Error() string { return e.err.Error() }
+func (e *TransportError) Unwrap() error { return e.err }
+
+// CMPv2 is a client capable of issuing certificates against an RFC 4210
+// CMPv2 certificate authority.
+type CMPv2 struct {
+	config CMPv2Config
+
+	httpClient *http.Client
+}
+
+// CMPv2Config holds everything the client needs to build, send and
+// validate a CMP enrollment exchange.
+type CMPv2Config struct {
+	URL          string
+	SenderKID    []byte
+	RecipientKID []byte
+
+	// Protection signs or MACs outgoing PKIMessages and verifies the
+	// protection on incoming ones.
+	Protection pkimessage.Protector
+}
+
+// New constructs a CMPv2 client for the given generic issuer, resolving its
+// CMPv2Issuer/CMPv2ClusterIssuer config and auth secret via secretsLister,
+// in the same spirit as vaultinternal.New.
+func New(namespace string, secretsLister corelisters.SecretLister, issuer v1alpha1.GenericIssuer) (*CMPv2, error) {
+	cmpSpec := issuer.GetSpec().CMPv2
+	if cmpSpec == nil {
+		return nil, fmt.Errorf("cmpv2 config may not be empty")
+	}
+
+	protection, err := pkimessage.NewProtector(namespace, secretsLister, cmpSpec.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise cmpv2 request protection: %s", err.Error())
+	}
+
+	httpClient := &http.Client{
+		Timeout: time.Second * 30,
+	}
+
+	if len(cmpSpec.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		if ok := pool.AppendCertsFromPEM(cmpSpec.CABundle); !ok {
+			return nil, fmt.Errorf("failed to parse caBundle for cmpv2 issuer")
+		}
+
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	return &CMPv2{
+		config: CMPv2Config{
+			URL:          cmpSpec.URL,
+			SenderKID:    []byte(cmpSpec.SenderKID),
+			RecipientKID: []byte(cmpSpec.RecipientKID),
+			Protection:   protection,
+		},
+		httpClient: httpClient,
+	}, nil
+}
+
+// Sign submits csrPEM to the configured CMPv2 CA as an `ir` PKIMessage,
+// waits for the `ip` response, confirms it with `certConf`/PKIConfirm, and
+// returns the issued leaf certificate along with the CA chain extracted
+// from the response's extraCerts field.
+func (c *CMPv2) Sign(csrPEM []byte, duration time.Duration) ([]byte, []byte, error) {
+	req, transactionID, err := pkimessage.BuildIR(csrPEM, c.config.SenderKID, c.config.RecipientKID, duration, c.config.Protection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build CMP ir request: %s", err.Error())
+	}
+
+	respBody, err := c.post(req)
+	if err != nil {
+		return nil, nil, &TransportError{err: fmt.Errorf("failed to send CMP ir request: %s", err.Error())}
+	}
+
+	certPEM, caPEM, confirm, err := pkimessage.ParseIP(respBody, c.config.Protection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CMP ip response: %s", err.Error())
+	}
+
+	confBody, err := pkimessage.BuildCertConf(confirm, c.config.SenderKID, c.config.RecipientKID, transactionID, c.config.Protection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build CMP certConf request: %s", err.Error())
+	}
+
+	ackBody, err := c.post(confBody)
+	if err != nil {
+		return nil, nil, &TransportError{err: fmt.Errorf("failed to send CMP certConf request: %s", err.Error())}
+	}
+
+	if err := pkimessage.ParsePKIConfirm(ackBody); err != nil {
+		return nil, nil, fmt.Errorf("CA rejected certConf: %s", err.Error())
+	}
+
+	return certPEM, caPEM, nil
+}
+
+func (c *CMPv2) post(body []byte) ([]byte, error) {
+	resp, err := c.httpClient.Post(c.config.URL, cmpContentType, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from CMPv2 server: %s", resp.StatusCode, string(data))
+	}
+
+	return data, nil
+}
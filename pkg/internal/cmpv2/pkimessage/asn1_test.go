@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkimessage
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRetagUntagBodyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		bodyType int
+	}{
+		{"ir", bodyTypeIR},
+		{"ip", bodyTypeIP},
+		{"pkiconf", bodyTypePKIConf},
+		{"certconf", bodyTypeCertConf},
+	}
+
+	original := []byte{0x30, 0x05, 0x02, 0x01, 0x01, 0xA0, 0x00}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tagged := retagBody(original, test.bodyType)
+
+			wantTag := byte(0xA0 | test.bodyType)
+			if tagged[0] != wantTag {
+				t.Fatalf("retagBody() leading tag = %#x, want %#x", tagged[0], wantTag)
+			}
+			if !bytes.Equal(tagged[1:], original[1:]) {
+				t.Fatalf("retagBody() altered body content: got %x, want %x", tagged[1:], original[1:])
+			}
+
+			untagged := untagBody(tagged)
+			if !bytes.Equal(untagged, original) {
+				t.Fatalf("untagBody(retagBody(x)) = %x, want original %x", untagged, original)
+			}
+		})
+	}
+}
+
+func TestRetagBodyEmpty(t *testing.T) {
+	if got := retagBody(nil, bodyTypeIR); len(got) != 0 {
+		t.Fatalf("retagBody(nil) = %x, want empty", got)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pkimessage builds and parses the RFC 4210 PKIMessage exchanges
+// required for CMPv2 certificate enrollment: `ir`/`cr` requests, `ip`/`cp`
+// responses, and the `certConf`/`PKIConfirm` acknowledgement.
+package pkimessage
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+)
+
+// Protector applies and verifies PKIMessage protection, either via
+// password-based MAC (shared secret) or a detached signature.
+type Protector interface {
+	// Protect computes the protection value over protectedBytes.
+	Protect(protectedBytes []byte) ([]byte, error)
+	// Verify checks protection over protectedBytes.
+	Verify(protectedBytes, protection []byte) error
+}
+
+// NewProtector builds the Protector configured by auth, resolving any
+// referenced Secret through secretsLister the same way the rest of the
+// issuer controllers resolve auth material.
+func NewProtector(namespace string, secretsLister corelisters.SecretLister, auth v1alpha1.CMPv2Auth) (Protector, error) {
+	switch {
+	case auth.SharedSecret != nil:
+		secret, err := secretsLister.Secrets(namespace).Get(auth.SharedSecret.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shared secret: %s", err.Error())
+		}
+
+		key, ok := secret.Data[auth.SharedSecret.Key]
+		if !ok {
+			return nil, fmt.Errorf("no data for %q in secret %q", auth.SharedSecret.Key, auth.SharedSecret.Name)
+		}
+
+		return &pbmProtector{sharedSecret: key}, nil
+
+	case auth.Signature != nil:
+		secret, err := secretsLister.Secrets(namespace).Get(auth.Signature.SecretName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signer secret: %s", err.Error())
+		}
+
+		certPEM, keyPEM := secret.Data["tls.crt"], secret.Data["tls.key"]
+		cert, err := parseLeafCertificate(certPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signer certificate: %s", err.Error())
+		}
+
+		key, err := parsePrivateKey(keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signer key: %s", err.Error())
+		}
+
+		return &signatureProtector{cert: cert, key: key}, nil
+
+	default:
+		return nil, fmt.Errorf("no CMPv2 auth method configured, one of sharedSecretSecretRef or signature is required")
+	}
+}
+
+// BuildIR constructs an `ir` PKIMessage wrapping csrPEM, addressed using
+// senderKID/recipientKID, requesting a certificate valid for duration, and
+// protected with protector. It returns the encoded message along with the
+// transactionID it generated, which the caller must echo back unchanged in
+// the subsequent BuildCertConf call so the CA can correlate the exchange.
+func BuildIR(csrPEM, senderKID, recipientKID []byte, duration time.Duration, protector Protector) (msg []byte, transactionID []byte, err error) {
+	csr, err := parseCertificateRequest(csrPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode CSR: %s", err.Error())
+	}
+
+	transactionID = make([]byte, 16)
+	if _, err := rand.Read(transactionID); err != nil {
+		return nil, nil, err
+	}
+
+	body := certReqMessage{
+		CertReq:       csr.Raw,
+		SenderKID:     senderKID,
+		RecipKID:      recipientKID,
+		TransactionID: transactionID,
+		NotAfter:      time.Now().Add(duration),
+	}
+
+	msg, err = protectAndEncode(body, bodyTypeIR, senderKID, recipientKID, transactionID, protector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msg, transactionID, nil
+}
+
+// ParseIP parses an `ip` PKIMessage, verifying its protection, and returns
+// the issued leaf certificate PEM, the CA chain PEM extracted from
+// extraCerts, and the confirmation material (certHash) the caller must echo
+// back in certConf.
+func ParseIP(data []byte, protector Protector) (certPEM, caPEM []byte, certConf []byte, err error) {
+	msg, err := decodePKIMessage(data, bodyTypeIP)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := protector.Verify(msg.ProtectedBytes, msg.Protection); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to verify response protection: %s", err.Error())
+	}
+
+	if msg.PKIStatus != statusAccepted {
+		return nil, nil, nil, fmt.Errorf("CA did not accept certificate request: status=%d, freeText=%s", msg.PKIStatus, msg.StatusString)
+	}
+
+	certPEM = encodeCertPEM(msg.IssuedCertDER)
+	caPEM = encodeCertChainPEM(msg.ExtraCertsDER)
+	certConf = computeCertHash(msg.IssuedCertDER)
+
+	return certPEM, caPEM, certConf, nil
+}
+
+// BuildCertConf constructs the `certConf` PKIMessage that must be sent to
+// acknowledge receipt of a successful `ip` response, as required by
+// RFC 4210 section 5.3.18. transactionID must be the same value returned by
+// the BuildIR call that started this exchange, so the CA can correlate the
+// confirmation with the request it answers.
+func BuildCertConf(certHash, senderKID, recipientKID, transactionID []byte, protector Protector) ([]byte, error) {
+	body := certConfMessage{
+		CertHash: certHash,
+	}
+
+	return protectAndEncode(body, bodyTypeCertConf, senderKID, recipientKID, transactionID, protector)
+}
+
+// ParsePKIConfirm parses the CA's final PKIConfirm response, returning an
+// error if the CA reports that confirmation was rejected.
+func ParsePKIConfirm(data []byte) error {
+	msg, err := decodePKIMessage(data, bodyTypePKIConf)
+	if err != nil {
+		return err
+	}
+
+	if msg.PKIStatus != statusAccepted && msg.PKIStatus != statusConfirmed {
+		return fmt.Errorf("CA rejected certConf: status=%d, freeText=%s", msg.PKIStatus, msg.StatusString)
+	}
+
+	return nil
+}
+
+func encodeCertPEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeCertChainPEM(ders [][]byte) []byte {
+	var out []byte
+	for _, der := range ders {
+		out = append(out, encodeCertPEM(der)...)
+	}
+	return out
+}
+
+func parseLeafCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block containing certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
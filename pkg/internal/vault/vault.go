@@ -0,0 +1,433 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vault implements a client that can request certificates from a
+// HashiCorp Vault PKI secrets engine.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+)
+
+// Client is a minimal client for Vault's PKI secrets engine, wrapping a
+// vaultapi.Client authenticated using whichever auth method is configured
+// on the issuer (AppRole, token or Kubernetes service account).
+type Client struct {
+	config v1alpha1.VaultIssuer
+	client *vaultapi.Client
+}
+
+// New constructs a Vault client for the given generic issuer, performing a
+// fresh login every call. Callers that sign or revoke at any volume should
+// prefer Login + NewWithToken so that a cached, still-valid token can be
+// reused across calls instead of re-authenticating to Vault every time.
+//
+// secretsLister resolves auth Secrets that live in the namespaces the
+// controller already watches. secondaryClient, if non-nil, is used instead
+// to fetch auth Secrets directly from the API server (bypassing the
+// informer cache) whenever the issuer's auth config carries an explicit
+// cross-namespace secretRef — this lets operators centralize Vault
+// credentials in a single namespace rather than duplicating them into
+// every namespace that defines an issuer.
+func New(namespace string, secretsLister corelisters.SecretLister, secondaryClient kubernetes.Interface, issuer v1alpha1.GenericIssuer) (*Client, error) {
+	vaultSpec := issuer.GetSpec().Vault
+	if vaultSpec == nil {
+		return nil, fmt.Errorf("vault config may not be empty")
+	}
+
+	vClient, err := newAPIClient(vaultSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := login(namespace, secretsLister, secondaryClient, vClient, vaultSpec, IsClusterScoped(issuer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to login to vault: %s", err.Error())
+	}
+
+	vClient.SetToken(token)
+
+	return &Client{
+		config: *vaultSpec,
+		client: vClient,
+	}, nil
+}
+
+// IsClusterScoped reports whether issuer is a ClusterIssuer. Cross-namespace
+// secretRefs backed by secretAccessGrants, per types_issuer_vault.go's
+// SecretAccessGrants doc comment, are only permitted for ClusterIssuers; a
+// namespaced Issuer may only ever reference Secrets in its own namespace,
+// grants or no grants. Exported so callers outside this package (e.g. the
+// certificaterequests/vault token cache) can apply the same gating when
+// resolving a Secret for cache-keying rather than for login.
+func IsClusterScoped(issuer v1alpha1.GenericIssuer) bool {
+	_, ok := issuer.(*v1alpha1.ClusterIssuer)
+	return ok
+}
+
+// NewWithToken constructs a Vault client for vaultSpec that presents token
+// directly, without performing a login. Used to resume a cached, still
+// valid token rather than re-authenticating on every call.
+func NewWithToken(vaultSpec *v1alpha1.VaultIssuer, token string) (*Client, error) {
+	vClient, err := newAPIClient(vaultSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	vClient.SetToken(token)
+
+	return &Client{
+		config: *vaultSpec,
+		client: vClient,
+	}, nil
+}
+
+// Login authenticates to Vault using whichever auth method is configured on
+// vaultSpec and returns the resulting TokenInfo (token, lease duration,
+// renewability and granted policies), as observed via auth/token/lookup-self.
+// Exported so callers can cache the result across Sign/Revoke calls instead
+// of logging in from scratch every time. issuer is used only to decide
+// whether cross-namespace secretRefs may be honoured; see IsClusterScoped.
+func Login(namespace string, secretsLister corelisters.SecretLister, secondaryClient kubernetes.Interface, issuer v1alpha1.GenericIssuer) (*TokenInfo, error) {
+	vaultSpec := issuer.GetSpec().Vault
+	if vaultSpec == nil {
+		return nil, fmt.Errorf("vault config may not be empty")
+	}
+
+	vClient, err := newAPIClient(vaultSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := login(namespace, secretsLister, secondaryClient, vClient, vaultSpec, IsClusterScoped(issuer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to login to vault: %s", err.Error())
+	}
+
+	vClient.SetToken(token)
+
+	return LookupSelf(vClient)
+}
+
+// RenewSelf renews token via auth/token/renew-self and returns the
+// refreshed TokenInfo.
+func RenewSelf(vaultSpec *v1alpha1.VaultIssuer, token string) (*TokenInfo, error) {
+	vClient, err := newAPIClient(vaultSpec)
+	if err != nil {
+		return nil, err
+	}
+	vClient.SetToken(token)
+
+	secret, err := vClient.Auth().Token().RenewSelf(0)
+	if err != nil {
+		return nil, fmt.Errorf("error renewing vault token: %s", err.Error())
+	}
+
+	info, err := tokenInfoFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	info.Token = token
+
+	return info, nil
+}
+
+// LookupSelf calls auth/token/lookup-self to fetch the lease duration,
+// renewability and policies of the token currently set on vClient.
+func LookupSelf(vClient *vaultapi.Client) (*TokenInfo, error) {
+	secret, err := vClient.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, fmt.Errorf("error looking up vault token: %s", err.Error())
+	}
+
+	info, err := tokenInfoFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	info.Token = vClient.Token()
+
+	return info, nil
+}
+
+// TokenInfo is everything the token cache needs to decide whether a
+// previously issued Vault token can still be reused.
+type TokenInfo struct {
+	Token string
+
+	// LeaseDuration is how long, from when it was issued/renewed, this
+	// token remains valid.
+	LeaseDuration time.Duration
+
+	// Renewable reports whether RenewSelf can extend this token's life
+	// rather than requiring a fresh login.
+	Renewable bool
+
+	// Policies are the Vault policies attached to this token, recorded for
+	// observability. They aren't used to gate whether a cached token is
+	// reused: Vault enforces path authorization itself on each request, and
+	// policy names carry no reliable convention the client can check against
+	// a PKI path ahead of time.
+	Policies []string
+}
+
+func tokenInfoFromSecret(secret *vaultapi.Secret) (*TokenInfo, error) {
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("empty response from vault token lookup")
+	}
+
+	leaseSeconds, _ := secret.Data["ttl"].(json.Number)
+	seconds, _ := leaseSeconds.Int64()
+
+	renewable, _ := secret.Data["renewable"].(bool)
+
+	var policies []string
+	if raw, ok := secret.Data["policies"].([]interface{}); ok {
+		for _, p := range raw {
+			if s, ok := p.(string); ok {
+				policies = append(policies, s)
+			}
+		}
+	}
+
+	return &TokenInfo{
+		LeaseDuration: time.Duration(seconds) * time.Second,
+		Renewable:     renewable,
+		Policies:      policies,
+	}, nil
+}
+
+func newAPIClient(vaultSpec *v1alpha1.VaultIssuer) (*vaultapi.Client, error) {
+	cfg := vaultapi.DefaultConfig()
+	cfg.Address = vaultSpec.Server
+
+	if len(vaultSpec.CABundle) > 0 {
+		if err := cfg.ConfigureTLS(&vaultapi.TLSConfig{CACertBytes: vaultSpec.CABundle}); err != nil {
+			return nil, fmt.Errorf("failed to configure vault client TLS: %s", err.Error())
+		}
+	}
+
+	vClient, err := vaultapi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise vault client: %s", err.Error())
+	}
+
+	return vClient, nil
+}
+
+// Sign requests a certificate for csrPEM from Vault's pki/sign/<role>
+// endpoint and returns the issued leaf certificate and CA chain PEMs.
+func (c *Client) Sign(csrPEM []byte, duration time.Duration) ([]byte, []byte, error) {
+	path := c.config.Path
+
+	resp, err := c.client.Logical().Write(path, map[string]interface{}{
+		"csr":         string(csrPEM),
+		"ttl":         duration.String(),
+		"common_name": "",
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error signing certificate in vault: %s", err.Error())
+	}
+
+	if resp == nil || resp.Data == nil {
+		return nil, nil, fmt.Errorf("empty response from vault pki sign request")
+	}
+
+	certPEM, ok := resp.Data["certificate"].(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("no certificate returned by vault")
+	}
+
+	caPEM, _ := resp.Data["issuing_ca"].(string)
+
+	return []byte(certPEM), []byte(caPEM), nil
+}
+
+// AuthSecretRef returns the name, identifying auth method, and namespace
+// (empty if not cross-namespace) of the Secret that vaultSpec's configured
+// auth method reads from. Used by callers that need to key a cache on the
+// auth Secret's identity/ResourceVersion without performing a full login.
+func AuthSecretRef(vaultSpec *v1alpha1.VaultIssuer) (secretName, authMethod, secretNamespace string, err error) {
+	switch {
+	case vaultSpec.Auth.TokenSecretRef != nil:
+		ref := vaultSpec.Auth.TokenSecretRef
+		return ref.Name, "token", ref.Namespace, nil
+	case vaultSpec.Auth.AppRole != nil:
+		ref := vaultSpec.Auth.AppRole.SecretRef
+		return ref.Name, "approle", ref.Namespace, nil
+	case vaultSpec.Auth.Kubernetes != nil:
+		ref := vaultSpec.Auth.Kubernetes.SecretRef
+		return ref.Name, "kubernetes", ref.Namespace, nil
+	}
+
+	return "", "", "", fmt.Errorf("no vault auth method configured")
+}
+
+// AuthSecret resolves the Secret that vaultSpec's configured auth method
+// reads from, applying the same cross-namespace/secretAccessGrants gating
+// that login does. Exported so callers that need the Secret itself (e.g. to
+// key a cache on its ResourceVersion) don't have to bypass that gating by
+// going straight to secretsLister, which would fail (or read the wrong
+// Secret) for any cross-namespace Vault auth ref.
+func AuthSecret(namespace string, secretsLister corelisters.SecretLister, secondaryClient kubernetes.Interface, vaultSpec *v1alpha1.VaultIssuer, clusterScoped bool) (*corev1.Secret, error) {
+	name, _, refNamespace, err := AuthSecretRef(vaultSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	return getSecret(namespace, name, refNamespace, secretsLister, secondaryClient, vaultSpec.SecretAccessGrants, clusterScoped)
+}
+
+// Revoke calls Vault's pki/revoke endpoint for the given certificate serial
+// number, as extracted from a previously issued certificate.
+func (c *Client) Revoke(serialNumber string) error {
+	mount := vaultMountFromSignPath(c.config.Path)
+	path := fmt.Sprintf("%s/revoke", mount)
+
+	_, err := c.client.Logical().Write(path, map[string]interface{}{
+		"serial_number": serialNumber,
+	})
+	if err != nil {
+		return fmt.Errorf("error revoking certificate in vault: %s", err.Error())
+	}
+
+	return nil
+}
+
+// vaultMountFromSignPath strips the trailing "sign/<role>" segment off a
+// configured Path (e.g. "pki/sign/my-role" -> "pki"), since revoke lives
+// directly under the mount rather than under a role.
+func vaultMountFromSignPath(signPath string) string {
+	if idx := strings.Index(signPath, "/sign/"); idx >= 0 {
+		return signPath[:idx]
+	}
+	return signPath
+}
+
+// getSecret abstracts fetching a Secret either through the shared informer
+// cache or, for cross-namespace refs, directly from the API server via the
+// secondary client. Cross-namespace refs are only honoured for ClusterIssuer
+// config (clusterScoped); a namespaced Issuer is rejected outright,
+// regardless of what secretAccessGrants it carries, since granting a
+// namespaced Issuer access to another namespace's Secrets would let any
+// tenant able to create an Issuer read Secrets they don't own.
+func getSecret(namespace, name, refNamespace string, secretsLister corelisters.SecretLister, secondaryClient kubernetes.Interface, grants []v1alpha1.SecretAccessGrant, clusterScoped bool) (*corev1.Secret, error) {
+	if refNamespace != "" && refNamespace != namespace {
+		if !clusterScoped {
+			return nil, fmt.Errorf("secretRef for %q in namespace %q is cross-namespace, which is only permitted for a ClusterIssuer", name, refNamespace)
+		}
+
+		if secondaryClient == nil {
+			return nil, fmt.Errorf("secretRef for %q in namespace %q is cross-namespace but no secondary client is configured; pass --enable-cross-namespace-issuer-secrets", name, refNamespace)
+		}
+
+		if !grantsAccess(grants, refNamespace, name) {
+			return nil, fmt.Errorf("secretRef for %q in namespace %q is not permitted by any secretAccessGrants entry", name, refNamespace)
+		}
+
+		return secondaryClient.CoreV1().Secrets(refNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+
+	return secretsLister.Secrets(namespace).Get(name)
+}
+
+func grantsAccess(grants []v1alpha1.SecretAccessGrant, namespace, name string) bool {
+	for _, g := range grants {
+		if g.Namespace == namespace && g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// login authenticates to Vault using whichever auth method is configured on
+// vaultSpec and returns the resulting client token. clusterScoped gates
+// whether a cross-namespace secretRef may be honoured; see getSecret.
+func login(namespace string, secretsLister corelisters.SecretLister, secondaryClient kubernetes.Interface, client *vaultapi.Client, vaultSpec *v1alpha1.VaultIssuer, clusterScoped bool) (string, error) {
+	grants := vaultSpec.SecretAccessGrants
+	switch {
+	case vaultSpec.Auth.TokenSecretRef != nil:
+		ref := vaultSpec.Auth.TokenSecretRef
+		secret, err := getSecret(namespace, ref.Name, ref.Namespace, secretsLister, secondaryClient, grants, clusterScoped)
+		if err != nil {
+			return "", fmt.Errorf("failed to get token secret: %s", err.Error())
+		}
+
+		token, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("no data for %q in secret %q", ref.Key, ref.Name)
+		}
+
+		return string(token), nil
+
+	case vaultSpec.Auth.AppRole != nil:
+		appRole := vaultSpec.Auth.AppRole
+		secret, err := getSecret(namespace, appRole.SecretRef.Name, appRole.SecretRef.Namespace, secretsLister, secondaryClient, grants, clusterScoped)
+		if err != nil {
+			return "", fmt.Errorf("failed to get approle secret: %s", err.Error())
+		}
+
+		secretID, ok := secret.Data[appRole.SecretRef.Key]
+		if !ok {
+			return "", fmt.Errorf("no data for %q in secret %q", appRole.SecretRef.Key, appRole.SecretRef.Name)
+		}
+
+		resp, err := client.Logical().Write(fmt.Sprintf("auth/%s/login", appRole.Path), map[string]interface{}{
+			"role_id":   appRole.RoleID,
+			"secret_id": string(secretID),
+		})
+		if err != nil {
+			return "", fmt.Errorf("error logging in via approle: %s", err.Error())
+		}
+
+		return resp.Auth.ClientToken, nil
+
+	case vaultSpec.Auth.Kubernetes != nil:
+		k8sAuth := vaultSpec.Auth.Kubernetes
+		secret, err := getSecret(namespace, k8sAuth.SecretRef.Name, k8sAuth.SecretRef.Namespace, secretsLister, secondaryClient, grants, clusterScoped)
+		if err != nil {
+			return "", fmt.Errorf("failed to get kubernetes auth secret: %s", err.Error())
+		}
+
+		jwt, ok := secret.Data[k8sAuth.SecretRef.Key]
+		if !ok {
+			return "", fmt.Errorf("no data for %q in secret %q", k8sAuth.SecretRef.Key, k8sAuth.SecretRef.Name)
+		}
+
+		resp, err := client.Logical().Write(fmt.Sprintf("auth/%s/login", k8sAuth.Path), map[string]interface{}{
+			"role": k8sAuth.Role,
+			"jwt":  string(jwt),
+		})
+		if err != nil {
+			return "", fmt.Errorf("error logging in via kubernetes auth: %s", err.Error())
+		}
+
+		return resp.Auth.ClientToken, nil
+	}
+
+	return "", fmt.Errorf("no vault auth method configured")
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+)
+
+func newSecretLister(secrets ...*corev1.Secret) corelisters.SecretLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, s := range secrets {
+		indexer.Add(s)
+	}
+	return corelisters.NewSecretLister(indexer)
+}
+
+func TestGetSecretCrossNamespaceGating(t *testing.T) {
+	otherNSSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "vault-system"},
+		Data:       map[string][]byte{"token": []byte("s.abc")},
+	}
+	grant := []v1alpha1.SecretAccessGrant{{Namespace: "vault-system", Name: "vault-token"}}
+
+	tests := []struct {
+		name            string
+		clusterScoped   bool
+		secondaryClient kubernetes.Interface
+		grants          []v1alpha1.SecretAccessGrant
+		wantErr         bool
+	}{
+		{
+			name:          "namespaced Issuer rejected outright, even with matching grants",
+			clusterScoped: false,
+			grants:        grant,
+			wantErr:       true,
+		},
+		{
+			name:          "ClusterIssuer without a secondary client rejected",
+			clusterScoped: true,
+			wantErr:       true,
+		},
+		{
+			name:            "ClusterIssuer without a matching grant rejected",
+			clusterScoped:   true,
+			secondaryClient: k8sfake.NewSimpleClientset(otherNSSecret),
+			wantErr:         true,
+		},
+		{
+			name:            "ClusterIssuer with a secondary client and matching grant allowed",
+			clusterScoped:   true,
+			secondaryClient: k8sfake.NewSimpleClientset(otherNSSecret),
+			grants:          grant,
+			wantErr:         false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := getSecret("issuer-ns", "vault-token", "vault-system", newSecretLister(), test.secondaryClient, test.grants, test.clusterScoped)
+			if (err != nil) != test.wantErr {
+				t.Errorf("getSecret() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetSecretSameNamespace(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "issuer-ns"},
+		Data:       map[string][]byte{"token": []byte("s.abc")},
+	}
+
+	got, err := getSecret("issuer-ns", "vault-token", "", newSecretLister(secret), nil, nil, false)
+	if err != nil {
+		t.Fatalf("getSecret() unexpected error: %s", err)
+	}
+	if got.Name != "vault-token" {
+		t.Errorf("getSecret() returned secret %q, want %q", got.Name, "vault-token")
+	}
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2019 The Jetstack cert-manager contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package issuer
+
+import (
+	"context"
+
+	"github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha1"
+)
+
+// Revoker is implemented by issuer backends that can revoke a certificate
+// they previously issued. Not all issuer types support revocation; those
+// that do implement this alongside Interface.
+type Revoker interface {
+	// Revoke revokes the certificate that was issued for cr, identified by
+	// the serial number of cr.Status.Certificate.
+	Revoke(ctx context.Context, cr *v1alpha1.CertificateRequest) error
+}